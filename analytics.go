@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	ClicksKey      = "shortlink:%s:clicks"
+	ClicksDailyKey = "shortlink:%s:clicks:%s"
+	RefererKey     = "shortlink:%s:referers"
+
+	maxReferers     = 20
+	statsDaysWindow = 30
+)
+
+// Analytics records visits against a shortlink and reports aggregated
+// stats for it. It is independent from Storage so a handler can be
+// wired to one without the other, or mocked separately in tests.
+type Analytics interface {
+	RecordVisit(eid string, ts time.Time, referer, ua, ip string)
+	Stats(ctx context.Context, eid string) (StatsReport, error)
+}
+
+// StatsReport is the payload returned by GET /api/info/:shortlink/stats.
+type StatsReport struct {
+	TotalClicks int64            `json:"total_clicks"`
+	DailyClicks map[string]int64 `json:"daily_clicks"`
+	TopReferers []string         `json:"top_referers"`
+}
+
+type RedisAnalytics struct {
+	Cli *redis.Client
+}
+
+func NewRedisAnalytics(addr string, passwd string, db int) *RedisAnalytics {
+	c := redis.NewClient(&redis.Options{
+		Addr:     addr,
+		Password: passwd,
+		DB:       db,
+	})
+
+	if _, err := c.Ping(context.Background()).Result(); err != nil {
+		panic(err)
+	}
+	return &RedisAnalytics{Cli: c}
+}
+
+// RecordVisit bumps the total click counter, the daily bucket for ts, and
+// pushes referer onto the capped recent-referers list. It is best-effort
+// and runs off the request path (the worker pool in visits.go), so it
+// isn't tied to any inbound request's context.
+func (a *RedisAnalytics) RecordVisit(eid string, ts time.Time, referer, ua, ip string) {
+	ctx := context.Background()
+
+	a.Cli.Incr(ctx, fmt.Sprintf(ClicksKey, eid))
+
+	day := ts.Format("20060102")
+	a.Cli.HIncrBy(ctx, fmt.Sprintf(ClicksDailyKey, eid, day), "count", 1)
+
+	if referer != "" {
+		refererKey := fmt.Sprintf(RefererKey, eid)
+		a.Cli.LPush(ctx, refererKey, referer)
+		a.Cli.LTrim(ctx, refererKey, 0, maxReferers-1)
+	}
+}
+
+func (a *RedisAnalytics) Stats(ctx context.Context, eid string) (StatsReport, error) {
+	report := StatsReport{
+		DailyClicks: make(map[string]int64, statsDaysWindow),
+	}
+
+	total, err := a.Cli.Get(ctx, fmt.Sprintf(ClicksKey, eid)).Int64()
+	if err != nil && err != redis.Nil {
+		return report, err
+	}
+	report.TotalClicks = total
+
+	now := time.Now()
+	for i := 0; i < statsDaysWindow; i++ {
+		day := now.AddDate(0, 0, -i).Format("20060102")
+		count, err := a.Cli.HGet(ctx, fmt.Sprintf(ClicksDailyKey, eid, day), "count").Int64()
+		if err != nil && err != redis.Nil {
+			return report, err
+		}
+		if count > 0 {
+			report.DailyClicks[day] = count
+		}
+	}
+
+	referers, err := a.Cli.LRange(ctx, fmt.Sprintf(RefererKey, eid), 0, maxReferers-1).Result()
+	if err != nil && err != redis.Nil {
+		return report, err
+	}
+	report.TopReferers = referers
+
+	return report, nil
+}
+
+// MemoryAnalytics is an in-process Analytics implementation with no
+// external dependency, mirroring storage/memory's role for Storage: it's
+// what SHORTLINK_BACKEND values other than "redis" run analytics against
+// instead of requiring a live Redis just to serve redirects.
+type MemoryAnalytics struct {
+	mu          sync.Mutex
+	clicks      map[string]int64
+	dailyClicks map[string]map[string]int64
+	referers    map[string][]string
+}
+
+func NewMemoryAnalytics() *MemoryAnalytics {
+	return &MemoryAnalytics{
+		clicks:      make(map[string]int64),
+		dailyClicks: make(map[string]map[string]int64),
+		referers:    make(map[string][]string),
+	}
+}
+
+func (a *MemoryAnalytics) RecordVisit(eid string, ts time.Time, referer, ua, ip string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.clicks[eid]++
+
+	day := ts.Format("20060102")
+	if a.dailyClicks[eid] == nil {
+		a.dailyClicks[eid] = make(map[string]int64)
+	}
+	a.dailyClicks[eid][day]++
+
+	if referer != "" {
+		refs := append([]string{referer}, a.referers[eid]...)
+		if len(refs) > maxReferers {
+			refs = refs[:maxReferers]
+		}
+		a.referers[eid] = refs
+	}
+}
+
+func (a *MemoryAnalytics) Stats(ctx context.Context, eid string) (StatsReport, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	report := StatsReport{
+		DailyClicks: make(map[string]int64, len(a.dailyClicks[eid])),
+		TopReferers: append([]string(nil), a.referers[eid]...),
+	}
+	report.TotalClicks = a.clicks[eid]
+	for day, count := range a.dailyClicks[eid] {
+		report.DailyClicks[day] = count
+	}
+
+	return report, nil
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMemoryAnalyticsRecordAndStats(t *testing.T) {
+	a := NewMemoryAnalytics()
+	ts := time.Date(2026, 7, 25, 12, 0, 0, 0, time.UTC)
+
+	a.RecordVisit("abc", ts, "https://example.com", "test-agent", "127.0.0.1")
+	a.RecordVisit("abc", ts, "https://example.com", "test-agent", "127.0.0.1")
+
+	report, err := a.Stats(context.Background(), "abc")
+	if err != nil {
+		t.Fatalf("should stat: %v", err)
+	}
+	if report.TotalClicks != 2 {
+		t.Fatalf("Excepted 2 total clicks, got %d", report.TotalClicks)
+	}
+	if report.DailyClicks["20260725"] != 2 {
+		t.Fatalf("Excepted 2 clicks on 20260725, got %d", report.DailyClicks["20260725"])
+	}
+	if len(report.TopReferers) != 2 || report.TopReferers[0] != "https://example.com" {
+		t.Fatalf("Excepted 2 top referers, got %v", report.TopReferers)
+	}
+}
+
+func TestMemoryAnalyticsStatsUnknownEID(t *testing.T) {
+	a := NewMemoryAnalytics()
+
+	report, err := a.Stats(context.Background(), "missing")
+	if err != nil {
+		t.Fatalf("should stat: %v", err)
+	}
+	if report.TotalClicks != 0 || len(report.DailyClicks) != 0 || len(report.TopReferers) != 0 {
+		t.Fatalf("Excepted an empty report, got %+v", report)
+	}
+}
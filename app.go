@@ -0,0 +1,147 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/astak16/shortlink/storage"
+)
+
+// App wires the router to the configured Storage and Analytics backends.
+type App struct {
+	Router     *mux.Router
+	S          Storage
+	A          Analytics
+	Auth       Auth
+	SigningKey []byte
+}
+
+type appHandler func(http.ResponseWriter, *http.Request) (int, error)
+
+func (fn appHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if status, err := fn(w, r); err != nil {
+		respondError(w, status, err.Error())
+	}
+}
+
+func (a *App) Initialize(e *Env) {
+	a.S = e.S
+	a.A = e.A
+	a.Auth = e.Auth
+	a.SigningKey = e.SigningKey
+	a.Router = mux.NewRouter()
+	a.initializeRoutes()
+	a.Router.Use(authMiddleware(a.Auth))
+}
+
+func (a *App) initializeRoutes() {
+	a.Router.Handle("/api/shorten", appHandler(a.createShortlink)).Methods("POST").Name("shorten")
+	a.Router.Handle("/api/info/{shortlink:[a-zA-Z0-9]+}/stats", appHandler(a.stats)).Methods("GET").Name("stats")
+	a.Router.Handle("/api/info/{shortlink:[a-zA-Z0-9]+}", appHandler(a.info)).Methods("GET")
+	// The charset matches SignCode's output: "<base62id>[.<exp>].<sig>",
+	// where sig is base64.RawURLEncoding (adds '-' and '_' to base62 + '.').
+	a.Router.Handle("/{shortlink:[a-zA-Z0-9_.-]+}", appHandler(a.redirect)).Methods("GET")
+}
+
+func (a *App) createShortlink(w http.ResponseWriter, r *http.Request) (int, error) {
+	var d struct {
+		URL                 string `json:"url"`
+		ExpirationInMinutes int64  `json:"expiration_in_minutes"`
+		CustomAlias         string `json:"custom_alias"`
+		Signed              bool   `json:"signed"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&d); err != nil {
+		return http.StatusBadRequest, err
+	}
+	defer r.Body.Close()
+
+	var eid string
+	var err error
+	switch {
+	case d.CustomAlias != "":
+		eid, err = a.S.ShortenCustom(r.Context(), d.URL, d.ExpirationInMinutes, d.CustomAlias)
+	case d.Signed:
+		eid, err = a.S.ShortenSigned(r.Context(), d.URL, d.ExpirationInMinutes)
+	default:
+		eid, err = a.S.Shorten(r.Context(), d.URL, d.ExpirationInMinutes)
+	}
+	if err != nil {
+		return toStatusCode(err), err
+	}
+
+	return respondJSON(w, http.StatusCreated, map[string]string{"shortlink": eid})
+}
+
+func (a *App) info(w http.ResponseWriter, r *http.Request) (int, error) {
+	vars := mux.Vars(r)
+	d, err := a.S.ShortlinkInfo(r.Context(), vars["shortlink"])
+	if err != nil {
+		return toStatusCode(err), err
+	}
+
+	return respondJSON(w, http.StatusOK, d)
+}
+
+func (a *App) redirect(w http.ResponseWriter, r *http.Request) (int, error) {
+	vars := mux.Vars(r)
+	code := vars["shortlink"]
+
+	eid := code
+	if strings.Contains(code, ".") {
+		id, _, ok := storage.VerifyCode(a.SigningKey, code)
+		if !ok {
+			return http.StatusBadRequest, errors.New("invalid or tampered shortlink")
+		}
+		eid = id
+	}
+
+	url, err := a.S.Unshorten(r.Context(), eid)
+	if err != nil {
+		return toStatusCode(err), err
+	}
+
+	if a.A != nil {
+		enqueueVisit(visit{a.A, eid, time.Now(), r.Referer(), r.UserAgent(), r.RemoteAddr})
+	}
+
+	http.Redirect(w, r, url, http.StatusTemporaryRedirect)
+	return http.StatusTemporaryRedirect, nil
+}
+
+func (a *App) stats(w http.ResponseWriter, r *http.Request) (int, error) {
+	vars := mux.Vars(r)
+	report, err := a.A.Stats(r.Context(), vars["shortlink"])
+	if err != nil {
+		return toStatusCode(err), err
+	}
+
+	return respondJSON(w, http.StatusOK, report)
+}
+
+func toStatusCode(err error) int {
+	if se, ok := err.(StatusError); ok {
+		return se.Code
+	}
+	return http.StatusInternalServerError
+}
+
+func respondJSON(w http.ResponseWriter, status int, payload interface{}) (int, error) {
+	response, err := json.Marshal(payload)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(response)
+	return status, nil
+}
+
+func respondError(w http.ResponseWriter, status int, message string) {
+	respondJSON(w, status, map[string]string{"error": message})
+}
@@ -2,15 +2,39 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/stretchr/testify/mock"
+
+	"github.com/astak16/shortlink/storage"
 )
 
+var testAuthKey = []byte("test-auth-secret")
+
+func signTestToken(t *testing.T, rights map[string][]string) string {
+	t.Helper()
+	claims := Claims{
+		Rights: rights,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		},
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(testAuthKey)
+	if err != nil {
+		t.Fatalf("should sign test token: %v", err)
+	}
+	return token
+}
+
 const (
 	expTime       = 60
 	longURL       = "https://www.baidu.com"
@@ -25,22 +49,50 @@ type storageMock struct {
 var app App
 var mockR *storageMock
 
-func (s *storageMock) Shorten(url string, exp int64) (string, error) {
-	args := s.Called(url, exp)
+func (s *storageMock) NextID(ctx context.Context) (int64, error) {
+	args := s.Called(ctx)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (s *storageMock) Shorten(ctx context.Context, url string, exp int64) (string, error) {
+	args := s.Called(ctx, url, exp)
 	fmt.Println(args)
 	return args.String(0), args.Error(1)
 }
 
-func (s *storageMock) Unshorten(eid string) (string, error) {
-	args := s.Called(eid)
+func (s *storageMock) ShortenCustom(ctx context.Context, url string, exp int64, alias string) (string, error) {
+	args := s.Called(ctx, url, exp, alias)
+	return args.String(0), args.Error(1)
+}
+
+func (s *storageMock) ShortenSigned(ctx context.Context, url string, exp int64) (string, error) {
+	args := s.Called(ctx, url, exp)
 	return args.String(0), args.Error(1)
 }
 
-func (s *storageMock) ShortlinkInfo(eid string) (interface{}, error) {
-	args := s.Called(eid)
+func (s *storageMock) Unshorten(ctx context.Context, eid string) (string, error) {
+	args := s.Called(ctx, eid)
 	return args.String(0), args.Error(1)
 }
 
+func (s *storageMock) ShortlinkInfo(ctx context.Context, eid string) (interface{}, error) {
+	args := s.Called(ctx, eid)
+	return args.String(0), args.Error(1)
+}
+
+type analyticsMock struct {
+	mock.Mock
+}
+
+func (a *analyticsMock) RecordVisit(eid string, ts time.Time, referer, ua, ip string) {
+	a.Called(eid, ts, referer, ua, ip)
+}
+
+func (a *analyticsMock) Stats(ctx context.Context, eid string) (StatsReport, error) {
+	args := a.Called(ctx, eid)
+	return args.Get(0).(StatsReport), args.Error(1)
+}
+
 func init() {
 	app = App{}
 	mockR = new(storageMock)
@@ -48,7 +100,7 @@ func init() {
 }
 
 func TestCreateShortlink(t *testing.T) {
-	mockR.On("Shorten", longURL, int64(expTime)).Return(shortLink, nil).Once()
+	mockR.On("Shorten", mock.Anything, longURL, int64(expTime)).Return(shortLink, nil).Once()
 
 	var jsonStr = []byte(`{"url":"https://www.baidu.com","expiration_in_minutes":60}`)
 	req, err := http.NewRequest("POST", "/api/shorten", bytes.NewBuffer(jsonStr))
@@ -75,13 +127,277 @@ func TestCreateShortlink(t *testing.T) {
 	}
 }
 
+func TestCreateShortlinkWithCustomAlias(t *testing.T) {
+	alias := "myAlias1"
+	mockR.On("ShortenCustom", mock.Anything, longURL, int64(expTime), alias).Return(alias, nil).Once()
+
+	var jsonStr = []byte(`{"url":"https://www.baidu.com","expiration_in_minutes":60,"custom_alias":"myAlias1"}`)
+	req, err := http.NewRequest("POST", "/api/shorten", bytes.NewBuffer(jsonStr))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	rw := httptest.NewRecorder()
+	app.Router.ServeHTTP(rw, req)
+
+	if rw.Code != http.StatusCreated {
+		t.Fatalf("Excepted status created, got %d", rw.Code)
+	}
+	resp := struct {
+		Shortlink string `json:"shortlink"`
+	}{}
+
+	if err := json.NewDecoder(rw.Body).Decode(&resp); err != nil {
+		t.Fatalf("should decode the response")
+	}
+	if resp.Shortlink != alias {
+		t.Fatalf("Excepted receive %s, got %s", alias, resp.Shortlink)
+	}
+}
+
+func TestCreateShortlinkSigned(t *testing.T) {
+	signedCode := shortLink + ".sig"
+	mockR.On("ShortenSigned", mock.Anything, longURL, int64(expTime)).Return(signedCode, nil).Once()
+
+	var jsonStr = []byte(`{"url":"https://www.baidu.com","expiration_in_minutes":60,"signed":true}`)
+	req, err := http.NewRequest("POST", "/api/shorten", bytes.NewBuffer(jsonStr))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	rw := httptest.NewRecorder()
+	app.Router.ServeHTTP(rw, req)
+
+	if rw.Code != http.StatusCreated {
+		t.Fatalf("Excepted status created, got %d", rw.Code)
+	}
+	resp := struct {
+		Shortlink string `json:"shortlink"`
+	}{}
+
+	if err := json.NewDecoder(rw.Body).Decode(&resp); err != nil {
+		t.Fatalf("should decode the response")
+	}
+	if resp.Shortlink != signedCode {
+		t.Fatalf("Excepted receive %s, got %s", signedCode, resp.Shortlink)
+	}
+}
+
+func TestCreateShortlinkRequiresAuth(t *testing.T) {
+	authedApp := App{}
+	authedApp.Initialize(&Env{S: mockR, Auth: NewJWTAuth(testAuthKey)})
+
+	var jsonStr = []byte(`{"url":"https://www.baidu.com","expiration_in_minutes":60}`)
+	req, err := http.NewRequest("POST", "/api/shorten", bytes.NewBuffer(jsonStr))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	rw := httptest.NewRecorder()
+	authedApp.Router.ServeHTTP(rw, req)
+
+	if rw.Code != http.StatusUnauthorized {
+		t.Fatalf("Excepted unauthorized %d, got %d", http.StatusUnauthorized, rw.Code)
+	}
+}
+
+func TestCreateShortlinkWithInsufficientRights(t *testing.T) {
+	authedApp := App{}
+	authedApp.Initialize(&Env{S: mockR, Auth: NewJWTAuth(testAuthKey)})
+
+	token := signTestToken(t, map[string][]string{"GET": {"/stats"}})
+
+	var jsonStr = []byte(`{"url":"https://www.baidu.com","expiration_in_minutes":60}`)
+	req, err := http.NewRequest("POST", "/api/shorten", bytes.NewBuffer(jsonStr))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	rw := httptest.NewRecorder()
+	authedApp.Router.ServeHTTP(rw, req)
+
+	if rw.Code != http.StatusForbidden {
+		t.Fatalf("Excepted forbidden %d, got %d", http.StatusForbidden, rw.Code)
+	}
+}
+
+func TestCreateShortlinkWithValidToken(t *testing.T) {
+	authedApp := App{}
+	authedApp.Initialize(&Env{S: mockR, Auth: NewJWTAuth(testAuthKey)})
+
+	mockR.On("Shorten", mock.Anything, longURL, int64(expTime)).Return(shortLink, nil).Once()
+	token := signTestToken(t, map[string][]string{"POST": {"/api/shorten"}})
+
+	var jsonStr = []byte(`{"url":"https://www.baidu.com","expiration_in_minutes":60}`)
+	req, err := http.NewRequest("POST", "/api/shorten", bytes.NewBuffer(jsonStr))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	rw := httptest.NewRecorder()
+	authedApp.Router.ServeHTTP(rw, req)
+
+	if rw.Code != http.StatusCreated {
+		t.Fatalf("Excepted status created, got %d", rw.Code)
+	}
+}
+
+func TestRedirectCustomAliasNamedStatsIsPublic(t *testing.T) {
+	authedApp := App{}
+	authedApp.Initialize(&Env{S: mockR, Auth: NewJWTAuth(testAuthKey)})
+
+	mockR.On("Unshorten", mock.Anything, "stats").Return(longURL, nil).Once()
+
+	req, err := http.NewRequest("GET", "/stats", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rw := httptest.NewRecorder()
+	authedApp.Router.ServeHTTP(rw, req)
+
+	if rw.Code != http.StatusTemporaryRedirect {
+		t.Fatalf("Excepted a custom alias named \"stats\" to redirect without auth, got %d", rw.Code)
+	}
+}
+
+// TestRedirectSignedAcceptsBase64URLChars guards against the redirect
+// route's charset rejecting a real SignCode output before it ever reaches
+// VerifyCode: base64.RawURLEncoding can produce '-' and '_', so the route
+// must accept them too, not just the base62 eid charset.
+func TestRedirectSignedAcceptsBase64URLChars(t *testing.T) {
+	key := []byte("test-signing-key")
+
+	var code, eid string
+	for i := 0; i < 200; i++ {
+		candidateEID := strconv.Itoa(i)
+		candidateCode := storage.SignCode(key, candidateEID, 0)
+		if strings.ContainsAny(candidateCode, "-_") {
+			code, eid = candidateCode, candidateEID
+			break
+		}
+	}
+	if code == "" {
+		t.Fatal("should find a signed code containing '-' or '_' within 200 tries")
+	}
+
+	signedApp := App{}
+	signedApp.Initialize(&Env{S: mockR, SigningKey: key})
+	mockR.On("Unshorten", mock.Anything, eid).Return(longURL, nil).Once()
+
+	req, err := http.NewRequest("GET", "/"+code, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rw := httptest.NewRecorder()
+	signedApp.Router.ServeHTTP(rw, req)
+
+	if rw.Code != http.StatusTemporaryRedirect {
+		t.Fatalf("Excepted redirect %d for code %q, got %d", http.StatusTemporaryRedirect, code, rw.Code)
+	}
+}
+
+func TestRedirectSignedTamperedSignature(t *testing.T) {
+	req, err := http.NewRequest("GET", "/abc.bad", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rw := httptest.NewRecorder()
+	app.Router.ServeHTTP(rw, req)
+
+	if rw.Code != http.StatusBadRequest {
+		t.Fatalf("Excepted bad request %d, got %d", http.StatusBadRequest, rw.Code)
+	}
+}
+
+func TestStats(t *testing.T) {
+	mockA := new(analyticsMock)
+	statsApp := App{}
+	statsApp.Initialize(&Env{S: mockR, A: mockA})
+
+	report := StatsReport{
+		TotalClicks: 3,
+		DailyClicks: map[string]int64{"20260725": 3},
+		TopReferers: []string{"https://example.com"},
+	}
+	mockA.On("Stats", mock.Anything, shortLink).Return(report, nil).Once()
+
+	req, err := http.NewRequest("GET", fmt.Sprintf("/api/info/%s/stats", shortLink), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rw := httptest.NewRecorder()
+	statsApp.Router.ServeHTTP(rw, req)
+
+	if rw.Code != http.StatusOK {
+		t.Fatalf("Excepted status ok, got %d", rw.Code)
+	}
+
+	var got StatsReport
+	if err := json.NewDecoder(rw.Body).Decode(&got); err != nil {
+		t.Fatalf("should decode the response")
+	}
+	if got.TotalClicks != report.TotalClicks {
+		t.Fatalf("Excepted %d total clicks, got %d", report.TotalClicks, got.TotalClicks)
+	}
+}
+
+// TestRedirectRecordsVisitAsynchronously guards the actual wiring a
+// redirect is supposed to trigger: enqueueVisit hands the visit to
+// visitWorker, which calls RecordVisit off the request goroutine. A test
+// against MemoryAnalytics.RecordVisit directly wouldn't catch a broken
+// enqueueVisit/visitWorker wire-up, so this drives it through a real
+// GET request and waits on a channel for the async call to land.
+func TestRedirectRecordsVisitAsynchronously(t *testing.T) {
+	mockA := new(analyticsMock)
+	recorded := make(chan string, 1)
+	mockA.On("RecordVisit", shortLink, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) { recorded <- args.String(0) }).
+		Once()
+
+	visitApp := App{}
+	visitApp.Initialize(&Env{S: mockR, A: mockA})
+	mockR.On("Unshorten", mock.Anything, shortLink).Return(longURL, nil).Once()
+
+	req, err := http.NewRequest("GET", "/"+shortLink, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rw := httptest.NewRecorder()
+	visitApp.Router.ServeHTTP(rw, req)
+
+	if rw.Code != http.StatusTemporaryRedirect {
+		t.Fatalf("Excepted redirect %d, got %d", http.StatusTemporaryRedirect, rw.Code)
+	}
+
+	select {
+	case eid := <-recorded:
+		if eid != shortLink {
+			t.Fatalf("Excepted RecordVisit for %s, got %s", shortLink, eid)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Excepted RecordVisit to be called asynchronously within 1s")
+	}
+}
+
 func TestRedirect(t *testing.T) {
 	r := fmt.Sprintf("/%s", shortLink)
 	req, err := http.NewRequest("GET", r, nil)
 	if err != nil {
 		t.Fatal("Should be able to create a requrest.", err)
 	}
-	mockR.On("Unshorten", shortLink).Return(longURL, nil).Once()
+	mockR.On("Unshorten", mock.Anything, shortLink).Return(longURL, nil).Once()
 
 	rw := httptest.NewRecorder()
 	app.Router.ServeHTTP(rw, req)
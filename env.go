@@ -1,11 +1,60 @@
 package main
 
+import (
+	"fmt"
+	"os"
+
+	"github.com/astak16/shortlink/storage/memory"
+	"github.com/astak16/shortlink/storage/postgres"
+	"github.com/astak16/shortlink/storage/redis"
+)
+
 type Env struct {
-	S Storage
+	S          Storage
+	A          Analytics
+	Auth       Auth
+	SigningKey []byte
 }
 
 func getEnv() *Env {
-	r := NewRedisCli("my-redis:6379", "", 0)
+	key := []byte(os.Getenv("SHORTLINK_SECRET"))
+	backend := os.Getenv("SHORTLINK_BACKEND")
+
+	s, err := newStorage(backend, key)
+	if err != nil {
+		panic(err)
+	}
+
+	a := newAnalytics(backend)
+	auth := NewJWTAuth([]byte(os.Getenv("SHORTLINK_JWT_SECRET")))
+
+	return &Env{S: s, A: a, Auth: auth, SigningKey: key}
+}
+
+// newStorage selects the Storage backend named by SHORTLINK_BACKEND,
+// defaulting to redis to match the service's original behavior.
+func newStorage(backend string, signingKey []byte) (Storage, error) {
+	switch backend {
+	case "", "redis":
+		return redis.NewRedisCli("my-redis:6379", "", 0, signingKey), nil
+	case "memory":
+		return memory.NewMemoryStore(signingKey), nil
+	case "postgres":
+		return postgres.NewPostgresStore(os.Getenv("SHORTLINK_POSTGRES_DSN"), signingKey)
+	default:
+		return nil, fmt.Errorf("unknown SHORTLINK_BACKEND %q", backend)
+	}
+}
 
-	return &Env{S: r}
+// newAnalytics selects the Analytics backend to match SHORTLINK_BACKEND:
+// redis (the default) records visits in Redis, anything else falls back to
+// MemoryAnalytics so memory/postgres don't also need a live Redis just to
+// serve redirects.
+func newAnalytics(backend string) Analytics {
+	switch backend {
+	case "", "redis":
+		return NewRedisAnalytics("my-redis:6379", "", 0)
+	default:
+		return NewMemoryAnalytics()
+	}
 }
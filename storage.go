@@ -0,0 +1,10 @@
+package main
+
+import "github.com/astak16/shortlink/storage"
+
+// Storage, StatusError and URLDetail live in the storage package so every
+// backend (storage/redis, storage/memory, storage/postgres) can satisfy
+// and use them without importing package main.
+type Storage = storage.Storage
+type StatusError = storage.StatusError
+type URLDetail = storage.URLDetail
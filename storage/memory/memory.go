@@ -0,0 +1,164 @@
+// Package memory is an in-memory Storage implementation: no external
+// dependency, so it's what tests and local CI run against.
+package memory
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"regexp"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pilu/go-base62"
+
+	"github.com/astak16/shortlink/storage"
+)
+
+var aliasPattern = regexp.MustCompile(`^[0-9a-zA-Z]{1,30}$`)
+
+type entry struct {
+	url    string
+	detail storage.URLDetail
+}
+
+// MemoryStore keeps shortlinks in a map guarded by a RWMutex and evicts
+// them with time.AfterFunc instead of relying on a backend's own TTL.
+type MemoryStore struct {
+	mu         sync.RWMutex
+	byEID      map[string]entry
+	byHash     map[string]string
+	counter    int64
+	signingKey []byte
+}
+
+func NewMemoryStore(signingKey []byte) *MemoryStore {
+	return &MemoryStore{
+		byEID:      make(map[string]entry),
+		byHash:     make(map[string]string),
+		signingKey: signingKey,
+	}
+}
+
+// NextID hands out ids with an atomic counter, mirroring RedisCli's INCR.
+func (m *MemoryStore) NextID(ctx context.Context) (int64, error) {
+	return atomic.AddInt64(&m.counter, 1), nil
+}
+
+// Shorten claims the hash-to-eid mapping and the id counter under a
+// single write lock, so two concurrent calls for the same url always
+// converge on the same eid instead of each minting their own. It skips
+// past any id whose base62 encoding is already taken by a custom alias
+// instead of overwriting it.
+func (m *MemoryStore) Shorten(ctx context.Context, url string, exp int64) (string, error) {
+	h := toSha1(url)
+
+	m.mu.Lock()
+	if eid, ok := m.byHash[h]; ok {
+		m.mu.Unlock()
+		return eid, nil
+	}
+	var eid string
+	for {
+		eid = base62.Encode(int(atomic.AddInt64(&m.counter, 1)))
+		if _, taken := m.byEID[eid]; !taken {
+			break
+		}
+	}
+	m.byEID[eid] = entry{url: url}
+	m.byHash[h] = eid
+	m.mu.Unlock()
+
+	m.store(eid, h, url, exp)
+	return eid, nil
+}
+
+// ShortenCustom does not register the alias in byHash: a custom alias does
+// not participate in URL-hash dedup, so a later plain Shorten(url) call
+// still mints its own generated id rather than resolving to this alias.
+// redis and postgres follow the same rule (see their ShortenCustom).
+func (m *MemoryStore) ShortenCustom(ctx context.Context, url string, exp int64, alias string) (string, error) {
+	if !aliasPattern.MatchString(alias) {
+		return "", storage.StatusError{Code: 400, Err: errors.New("custom_alias must be 1-30 base62 characters")}
+	}
+
+	m.mu.Lock()
+	if _, taken := m.byEID[alias]; taken {
+		m.mu.Unlock()
+		return "", storage.StatusError{Code: 409, Err: errors.New("custom_alias is already taken")}
+	}
+	m.byEID[alias] = entry{url: url}
+	m.mu.Unlock()
+
+	m.store(alias, "", url, exp)
+	return alias, nil
+}
+
+func (m *MemoryStore) ShortenSigned(ctx context.Context, url string, exp int64) (string, error) {
+	eid, err := m.Shorten(ctx, url, exp)
+	if err != nil {
+		return "", err
+	}
+	return storage.SignCode(m.signingKey, eid, exp), nil
+}
+
+func (m *MemoryStore) ShortlinkInfo(ctx context.Context, eid string) (interface{}, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	e, ok := m.byEID[eid]
+	if !ok {
+		return "", storage.StatusError{Code: 404, Err: errors.New("Unknown short URL")}
+	}
+	return e.detail, nil
+}
+
+func (m *MemoryStore) Unshorten(ctx context.Context, eid string) (string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	e, ok := m.byEID[eid]
+	if !ok {
+		return "", storage.StatusError{Code: 404, Err: errors.New("Unknown short URL")}
+	}
+	return e.url, nil
+}
+
+// store writes the entry and, when exp is set, schedules its eviction. hash
+// is the byHash key that maps back to eid, empty for a custom alias, which
+// isn't deduped by hash.
+func (m *MemoryStore) store(eid, hash, url string, exp int64) {
+	detail := storage.URLDetail{
+		URL:                 url,
+		CreatedAt:           time.Now().String(),
+		ExpirationInMinutes: time.Duration(exp),
+	}
+
+	m.mu.Lock()
+	m.byEID[eid] = entry{url: url, detail: detail}
+	m.mu.Unlock()
+
+	if exp > 0 {
+		time.AfterFunc(time.Minute*time.Duration(exp), func() { m.evict(eid, hash) })
+	}
+}
+
+// evict drops eid and, when hash is set, its byHash entry, so a URL
+// resubmitted after its shortlink expires gets re-stored under a fresh eid
+// instead of resolving to the now-dead one.
+func (m *MemoryStore) evict(eid, hash string) {
+	m.mu.Lock()
+	delete(m.byEID, eid)
+	if hash != "" {
+		delete(m.byHash, hash)
+	}
+	m.mu.Unlock()
+}
+
+func toSha1(str string) string {
+	sha := sha1.New()
+	sha.Write([]byte(str))
+	return hex.EncodeToString(sha.Sum(nil))
+}
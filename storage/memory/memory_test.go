@@ -0,0 +1,203 @@
+package memory
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/pilu/go-base62"
+
+	"github.com/astak16/shortlink/storage"
+)
+
+func TestShortenAndUnshorten(t *testing.T) {
+	m := NewMemoryStore(nil)
+	ctx := context.Background()
+
+	eid, err := m.Shorten(ctx, "https://www.baidu.com", 60)
+	if err != nil {
+		t.Fatalf("should shorten: %v", err)
+	}
+
+	url, err := m.Unshorten(ctx, eid)
+	if err != nil {
+		t.Fatalf("should unshorten: %v", err)
+	}
+	if url != "https://www.baidu.com" {
+		t.Fatalf("Excepted https://www.baidu.com, got %s", url)
+	}
+}
+
+func TestShortenCustomRejectsCollision(t *testing.T) {
+	m := NewMemoryStore(nil)
+	ctx := context.Background()
+
+	if _, err := m.ShortenCustom(ctx, "https://www.baidu.com", 60, "taken"); err != nil {
+		t.Fatalf("should reserve the alias: %v", err)
+	}
+
+	if _, err := m.ShortenCustom(ctx, "https://www.qq.com", 60, "taken"); err == nil {
+		t.Fatal("Excepted a collision error, got nil")
+	}
+}
+
+// TestShortenCustomDoesNotDedupFutureShorten guards the cross-backend
+// contract that a custom alias does not participate in URL-hash dedup: a
+// later plain Shorten for the same url must mint its own generated eid
+// rather than resolving to the alias (redis and postgres follow the same
+// rule; see their ShortenCustom).
+func TestShortenCustomDoesNotDedupFutureShorten(t *testing.T) {
+	m := NewMemoryStore(nil)
+	ctx := context.Background()
+
+	if _, err := m.ShortenCustom(ctx, "https://www.baidu.com", 60, "mycustom"); err != nil {
+		t.Fatalf("should reserve the alias: %v", err)
+	}
+
+	eid, err := m.Shorten(ctx, "https://www.baidu.com", 60)
+	if err != nil {
+		t.Fatalf("should shorten: %v", err)
+	}
+	if eid == "mycustom" {
+		t.Fatal("Excepted Shorten to mint its own eid instead of deduping to the custom alias")
+	}
+}
+
+// TestShortenSkipsIDTakenByCustomAlias guards against Shorten overwriting a
+// custom alias whose base62 id happens to match the next counter value.
+func TestShortenSkipsIDTakenByCustomAlias(t *testing.T) {
+	m := NewMemoryStore(nil)
+	ctx := context.Background()
+
+	next, err := m.NextID(ctx)
+	if err != nil {
+		t.Fatalf("should reserve the next id: %v", err)
+	}
+	alias := base62.Encode(int(next + 1))
+	if _, err := m.ShortenCustom(ctx, "https://www.qq.com", 60, alias); err != nil {
+		t.Fatalf("should reserve the alias: %v", err)
+	}
+
+	eid, err := m.Shorten(ctx, "https://www.baidu.com", 60)
+	if err != nil {
+		t.Fatalf("should shorten: %v", err)
+	}
+	if eid == alias {
+		t.Fatalf("Excepted Shorten to skip the id taken by alias %s, got the same eid", alias)
+	}
+
+	url, err := m.Unshorten(ctx, alias)
+	if err != nil {
+		t.Fatalf("should unshorten the alias: %v", err)
+	}
+	if url != "https://www.qq.com" {
+		t.Fatalf("Excepted the alias to still point at https://www.qq.com, got %s", url)
+	}
+}
+
+// TestShortenResubmitsAfterEviction guards against evict clearing byEID but
+// leaving a stale byHash entry behind, which would make a resubmitted URL
+// resolve to a dead eid forever.
+func TestShortenResubmitsAfterEviction(t *testing.T) {
+	m := NewMemoryStore(nil)
+	ctx := context.Background()
+
+	eid, err := m.Shorten(ctx, "https://www.baidu.com", 60)
+	if err != nil {
+		t.Fatalf("should shorten: %v", err)
+	}
+
+	m.evict(eid, toSha1("https://www.baidu.com"))
+
+	if _, err := m.Unshorten(ctx, eid); err == nil {
+		t.Fatal("Excepted the evicted eid to be gone")
+	}
+
+	newEID, err := m.Shorten(ctx, "https://www.baidu.com", 60)
+	if err != nil {
+		t.Fatalf("should re-shorten after eviction: %v", err)
+	}
+	if newEID == eid {
+		t.Fatalf("Excepted a fresh eid after eviction, got the stale %s again", eid)
+	}
+	if _, err := m.Unshorten(ctx, newEID); err != nil {
+		t.Fatalf("should unshorten the fresh eid: %v", err)
+	}
+}
+
+// TestShortenSignedRoundTrip covers the ShortenSigned path: VerifyCode must
+// accept a freshly-signed code and recover the eid it was signed for.
+func TestShortenSignedRoundTrip(t *testing.T) {
+	key := []byte("test-signing-key")
+	m := NewMemoryStore(key)
+	ctx := context.Background()
+
+	code, err := m.ShortenSigned(ctx, "https://www.baidu.com", 60)
+	if err != nil {
+		t.Fatalf("should shorten: %v", err)
+	}
+
+	eid, exp, ok := storage.VerifyCode(key, code)
+	if !ok {
+		t.Fatal("Excepted VerifyCode to accept the freshly-signed code")
+	}
+	if exp != 60 {
+		t.Fatalf("Excepted exp 60, got %d", exp)
+	}
+
+	url, err := m.Unshorten(ctx, eid)
+	if err != nil {
+		t.Fatalf("should unshorten: %v", err)
+	}
+	if url != "https://www.baidu.com" {
+		t.Fatalf("Excepted https://www.baidu.com, got %s", url)
+	}
+}
+
+// TestShortenSignedRejectsWrongKey guards the tamper-detection this feature
+// exists for: a code verified with a different key must not validate.
+func TestShortenSignedRejectsWrongKey(t *testing.T) {
+	m := NewMemoryStore([]byte("test-signing-key"))
+	ctx := context.Background()
+
+	code, err := m.ShortenSigned(ctx, "https://www.baidu.com", 0)
+	if err != nil {
+		t.Fatalf("should shorten: %v", err)
+	}
+
+	if _, _, ok := storage.VerifyCode([]byte("wrong-key"), code); ok {
+		t.Fatal("Excepted VerifyCode to reject a code signed with a different key")
+	}
+}
+
+// TestShortenConcurrentSameURLProducesOneEID guards against the race the
+// go-redis v9 migration fixed in RedisCli.Shorten: firing N concurrent
+// Shorten calls for the same URL must converge on a single eid rather
+// than each minting its own.
+func TestShortenConcurrentSameURLProducesOneEID(t *testing.T) {
+	m := NewMemoryStore(nil)
+	ctx := context.Background()
+
+	const n = 50
+	eids := make([]string, n)
+	var wg sync.WaitGroup
+	wg.Add(n)
+	for i := 0; i < n; i++ {
+		go func(i int) {
+			defer wg.Done()
+			eid, err := m.Shorten(ctx, "https://www.baidu.com", 60)
+			if err != nil {
+				t.Errorf("should shorten: %v", err)
+				return
+			}
+			eids[i] = eid
+		}(i)
+	}
+	wg.Wait()
+
+	for i := 1; i < n; i++ {
+		if eids[i] != eids[0] {
+			t.Fatalf("Excepted every concurrent Shorten call to produce %s, got %s at index %d", eids[0], eids[i], i)
+		}
+	}
+}
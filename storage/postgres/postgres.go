@@ -0,0 +1,194 @@
+// Package postgres is the Postgres-backed Storage implementation.
+package postgres
+
+import (
+	"context"
+	"crypto/sha1"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"regexp"
+	"time"
+
+	_ "github.com/lib/pq"
+	"github.com/pilu/go-base62"
+
+	"github.com/astak16/shortlink/storage"
+)
+
+const schema = `
+CREATE TABLE IF NOT EXISTS shortlinks (
+	eid        TEXT PRIMARY KEY,
+	url        TEXT NOT NULL,
+	url_hash   TEXT,
+	created_at TIMESTAMP NOT NULL DEFAULT now(),
+	expires_at TIMESTAMP
+);
+CREATE SEQUENCE IF NOT EXISTS shortlinks_id_seq;
+CREATE INDEX IF NOT EXISTS shortlinks_url_hash_idx ON shortlinks (url_hash);
+`
+
+const sweepInterval = time.Minute
+
+var aliasPattern = regexp.MustCompile(`^[0-9a-zA-Z]{1,30}$`)
+
+// PostgresStore backs Storage with a shortlinks table and a sequence for
+// NextID, with a background sweeper that deletes expired rows.
+type PostgresStore struct {
+	DB         *sql.DB
+	signingKey []byte
+
+	stop chan struct{}
+}
+
+func NewPostgresStore(dsn string, signingKey []byte) (*PostgresStore, error) {
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, err
+	}
+	if err := db.PingContext(context.Background()); err != nil {
+		return nil, err
+	}
+	if _, err := db.Exec(schema); err != nil {
+		return nil, err
+	}
+
+	p := &PostgresStore{DB: db, signingKey: signingKey, stop: make(chan struct{})}
+	go p.sweep()
+	return p, nil
+}
+
+// Close stops the background sweeper and closes the underlying connection.
+func (p *PostgresStore) Close() error {
+	close(p.stop)
+	return p.DB.Close()
+}
+
+func (p *PostgresStore) sweep() {
+	ticker := time.NewTicker(sweepInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			p.DB.Exec(`DELETE FROM shortlinks WHERE expires_at IS NOT NULL AND expires_at < now()`)
+		case <-p.stop:
+			return
+		}
+	}
+}
+
+// NextID reserves the next base62 counter value from shortlinks_id_seq.
+func (p *PostgresStore) NextID(ctx context.Context) (int64, error) {
+	var id int64
+	err := p.DB.QueryRowContext(ctx, `SELECT nextval('shortlinks_id_seq')`).Scan(&id)
+	return id, err
+}
+
+// Shorten dedupes on url_hash and, on a PK collision between a freshly
+// minted eid and an already-reserved custom alias, mints another id and
+// retries rather than erroring out, mirroring memory/redis (see db84a3d).
+func (p *PostgresStore) Shorten(ctx context.Context, url string, exp int64) (string, error) {
+	h := toSha1(url)
+
+	var eid string
+	err := p.DB.QueryRowContext(ctx, `SELECT eid FROM shortlinks WHERE url_hash = $1`, h).Scan(&eid)
+	if err == nil {
+		return eid, nil
+	}
+	if err != sql.ErrNoRows {
+		return "", err
+	}
+
+	for {
+		id, err := p.NextID(ctx)
+		if err != nil {
+			return "", err
+		}
+		eid = base62.Encode(int(id))
+
+		err = p.insert(ctx, eid, url, h, exp)
+		if err == nil {
+			return eid, nil
+		}
+		if !isUniqueViolation(err) {
+			return "", err
+		}
+	}
+}
+
+// ShortenCustom does not participate in URL-hash dedup (see
+// memory.ShortenCustom), so its row gets no url_hash.
+func (p *PostgresStore) ShortenCustom(ctx context.Context, url string, exp int64, alias string) (string, error) {
+	if !aliasPattern.MatchString(alias) {
+		return "", storage.StatusError{Code: 400, Err: errors.New("custom_alias must be 1-30 base62 characters")}
+	}
+
+	if err := p.insert(ctx, alias, url, nil, exp); err != nil {
+		if isUniqueViolation(err) {
+			return "", storage.StatusError{Code: 409, Err: errors.New("custom_alias is already taken")}
+		}
+		return "", err
+	}
+	return alias, nil
+}
+
+func (p *PostgresStore) ShortenSigned(ctx context.Context, url string, exp int64) (string, error) {
+	eid, err := p.Shorten(ctx, url, exp)
+	if err != nil {
+		return "", err
+	}
+	return storage.SignCode(p.signingKey, eid, exp), nil
+}
+
+func (p *PostgresStore) ShortlinkInfo(ctx context.Context, eid string) (interface{}, error) {
+	var detail storage.URLDetail
+	var expiresAt sql.NullTime
+	err := p.DB.QueryRowContext(ctx,
+		`SELECT url, created_at, expires_at FROM shortlinks WHERE eid = $1`, eid,
+	).Scan(&detail.URL, &detail.CreatedAt, &expiresAt)
+	if err == sql.ErrNoRows {
+		return "", storage.StatusError{Code: 404, Err: errors.New("Unknown short URL")}
+	}
+	if err != nil {
+		return "", err
+	}
+	return detail, nil
+}
+
+func (p *PostgresStore) Unshorten(ctx context.Context, eid string) (string, error) {
+	var url string
+	err := p.DB.QueryRowContext(ctx, `SELECT url FROM shortlinks WHERE eid = $1`, eid).Scan(&url)
+	if err == sql.ErrNoRows {
+		return "", storage.StatusError{Code: 404, Err: err}
+	}
+	if err != nil {
+		return "", err
+	}
+	return url, nil
+}
+
+// insert writes a shortlinks row. urlHash should be a string to participate
+// in Shorten's dedup lookup, or nil to opt out (see ShortenCustom).
+func (p *PostgresStore) insert(ctx context.Context, eid, url string, urlHash interface{}, exp int64) error {
+	var expiresAt interface{}
+	if exp > 0 {
+		expiresAt = time.Now().Add(time.Minute * time.Duration(exp))
+	}
+
+	_, err := p.DB.ExecContext(ctx,
+		`INSERT INTO shortlinks (eid, url, url_hash, expires_at) VALUES ($1, $2, $3, $4)`,
+		eid, url, urlHash, expiresAt,
+	)
+	return err
+}
+
+func isUniqueViolation(err error) bool {
+	return err != nil && regexp.MustCompile(`duplicate key value`).MatchString(err.Error())
+}
+
+func toSha1(str string) string {
+	sha := sha1.New()
+	sha.Write([]byte(str))
+	return hex.EncodeToString(sha.Sum(nil))
+}
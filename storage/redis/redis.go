@@ -0,0 +1,233 @@
+// Package redis is the Redis-backed Storage implementation.
+package redis
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/pilu/go-base62"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/astak16/shortlink/storage"
+)
+
+const (
+	URLIDKEY           = "next.url.id"
+	ShortlinkKey       = "shortlink:%s:url"
+	URLHashKey         = "urlhash:%s:url"
+	ShortlinkDetailKey = "shortlink:%s:detail"
+)
+
+// aliasPattern mirrors the aicra string(1,30) type constraint: base62
+// characters only, between 1 and 30 of them.
+var aliasPattern = regexp.MustCompile(`^[0-9a-zA-Z]{1,30}$`)
+
+// shortenCustomScript atomically claims the alias and writes its
+// ShortlinkKey and ShortlinkDetailKey entries in one round-trip so a custom
+// alias can never collide with a concurrently reserved one. A custom alias
+// does not participate in URL-hash dedup (see memory.ShortenCustom), so it
+// gets no URLHashKey entry. KEYS[1..2] are the shortlink and detail keys;
+// ARGV[1] is the url, ARGV[2] the detail JSON and ARGV[3] the expiration in
+// seconds. ARGV[3] of 0 means "never expires", matching the rest of the
+// codebase, so the EXPIRE calls are skipped rather than deleting the keys
+// on the spot.
+const shortenCustomScript = `
+if redis.call("SETNX", KEYS[1], ARGV[1]) == 0 then
+	return 0
+end
+redis.call("SET", KEYS[2], ARGV[2])
+if tonumber(ARGV[3]) > 0 then
+	redis.call("EXPIRE", KEYS[1], ARGV[3])
+	redis.call("EXPIRE", KEYS[2], ARGV[3])
+end
+return 1
+`
+
+// claimIDScript atomically resolves the numeric id for a URL: it returns
+// the id already claimed for KEYS[1] (the urlhash key) if one exists, or
+// claims a fresh one from KEYS[2] (the counter) otherwise. Because the
+// check-and-claim happens inside one EVAL, concurrent Shorten calls for
+// the same URL always converge on the same id instead of each minting
+// their own.
+const claimIDScript = `
+local id = redis.call("GET", KEYS[1])
+if id then
+	return id
+end
+id = redis.call("INCR", KEYS[2])
+redis.call("SET", KEYS[1], id)
+return id
+`
+
+// claimSlotScript reserves a generated id's ShortlinkKey/ShortlinkDetailKey
+// with the same SETNX-based check shortenCustomScript uses for a custom
+// alias, since both paths write into the same key space and a generated id
+// can land on an alias that's already taken. KEYS[1..2] are the shortlink
+// and detail keys, KEYS[3] the urlhash key to repoint at this id once the
+// reservation succeeds; ARGV[1] is the url, ARGV[2] the detail JSON, ARGV[3]
+// the expiration in seconds (0 = never expires) and ARGV[4] the numeric id.
+// A slot that already holds this url while the urlhash key points at this
+// id is this URL's own earlier reservation and counts as a success, so a
+// repeat or concurrent Shorten for the same url gets the same eid back.
+const claimSlotScript = `
+if redis.call("SETNX", KEYS[1], ARGV[1]) == 0 then
+	if redis.call("GET", KEYS[1]) == ARGV[1] and redis.call("GET", KEYS[3]) == ARGV[4] then
+		return 1
+	end
+	return 0
+end
+redis.call("SET", KEYS[2], ARGV[2])
+redis.call("SET", KEYS[3], ARGV[4])
+if tonumber(ARGV[3]) > 0 then
+	redis.call("EXPIRE", KEYS[1], ARGV[3])
+	redis.call("EXPIRE", KEYS[2], ARGV[3])
+	redis.call("EXPIRE", KEYS[3], ARGV[3])
+end
+return 1
+`
+
+type RedisCli struct {
+	Cli        *redis.Client
+	SigningKey []byte
+}
+
+func NewRedisCli(addr string, passwd string, db int, signingKey []byte) *RedisCli {
+	c := redis.NewClient(&redis.Options{
+		Addr:     addr,
+		Password: passwd,
+		DB:       db,
+	})
+
+	if _, err := c.Ping(context.Background()).Result(); err != nil {
+		panic(err)
+	}
+	return &RedisCli{Cli: c, SigningKey: signingKey}
+}
+
+// NextID reserves the next base62 counter value via INCR.
+func (r *RedisCli) NextID(ctx context.Context) (int64, error) {
+	return r.Cli.Incr(ctx, URLIDKEY).Result()
+}
+
+// Shorten atomically claims an id for url (see claimIDScript) and reserves
+// its ShortlinkKey and ShortlinkDetailKey entries with claimSlotScript. If
+// the id's base62 encoding collides with an already-taken custom alias,
+// it mints another id and retries rather than overwriting that alias.
+func (r *RedisCli) Shorten(ctx context.Context, url string, exp int64) (string, error) {
+	hashKey := fmt.Sprintf(URLHashKey, toSha1(url))
+
+	id, err := r.Cli.Eval(ctx, claimIDScript, []string{hashKey, URLIDKEY}).Int64()
+	if err != nil {
+		return "", err
+	}
+
+	detail, err := json.Marshal(&storage.URLDetail{
+		URL:                 url,
+		CreatedAt:           time.Now().String(),
+		ExpirationInMinutes: time.Duration(exp),
+	})
+	if err != nil {
+		return "", err
+	}
+	ttlSeconds := int64((time.Minute * time.Duration(exp)).Seconds())
+
+	for {
+		eid := base62.Encode(int(id))
+
+		ok, err := r.Cli.Eval(ctx, claimSlotScript, []string{
+			fmt.Sprintf(ShortlinkKey, eid),
+			fmt.Sprintf(ShortlinkDetailKey, eid),
+			hashKey,
+		}, url, string(detail), ttlSeconds, id).Int64()
+		if err != nil {
+			return "", err
+		}
+		if ok == 1 {
+			return eid, nil
+		}
+
+		if id, err = r.Cli.Incr(ctx, URLIDKEY).Result(); err != nil {
+			return "", err
+		}
+	}
+}
+
+// ShortenSigned behaves like Shorten but returns a code that embeds a
+// keyed HMAC of the id (and, when exp is set, the expiration), so the
+// redirect handler can reject a forged or tampered id without a Redis
+// round-trip. See storage.SignCode/storage.VerifyCode.
+func (r *RedisCli) ShortenSigned(ctx context.Context, url string, exp int64) (string, error) {
+	eid, err := r.Shorten(ctx, url, exp)
+	if err != nil {
+		return "", err
+	}
+
+	return storage.SignCode(r.SigningKey, eid, exp), nil
+}
+
+func (r *RedisCli) ShortenCustom(ctx context.Context, url string, exp int64, alias string) (string, error) {
+	if !aliasPattern.MatchString(alias) {
+		return "", storage.StatusError{Code: 400, Err: errors.New("custom_alias must be 1-30 base62 characters")}
+	}
+
+	detail, err := json.Marshal(&storage.URLDetail{
+		URL:                 url,
+		CreatedAt:           time.Now().String(),
+		ExpirationInMinutes: time.Duration(exp),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	ok, err := r.Cli.Eval(ctx, shortenCustomScript, []string{
+		fmt.Sprintf(ShortlinkKey, alias),
+		fmt.Sprintf(ShortlinkDetailKey, alias),
+	}, url, string(detail), int64((time.Minute * time.Duration(exp)).Seconds())).Int64()
+	if err != nil {
+		return "", err
+	}
+	if ok == 0 {
+		return "", storage.StatusError{Code: 409, Err: errors.New("custom_alias is already taken")}
+	}
+
+	return alias, nil
+}
+
+func (r *RedisCli) ShortlinkInfo(ctx context.Context, eid string) (interface{}, error) {
+	d, err := r.Cli.Get(ctx, fmt.Sprintf(ShortlinkDetailKey, eid)).Result()
+	if err == redis.Nil {
+		return "", storage.StatusError{Code: 404, Err: errors.New("Unknown short URL")}
+	} else if err != nil {
+		return "", err
+	}
+
+	var detail storage.URLDetail
+	if err := json.Unmarshal([]byte(d), &detail); err != nil {
+		return "", err
+	}
+	return detail, nil
+}
+
+func (r *RedisCli) Unshorten(ctx context.Context, eid string) (string, error) {
+	url, err := r.Cli.Get(ctx, fmt.Sprintf(ShortlinkKey, eid)).Result()
+	if err == redis.Nil {
+		return "", storage.StatusError{Code: 404, Err: err}
+	} else if err != nil {
+		return "", err
+	} else {
+		return url, nil
+	}
+}
+
+// ToSha1 ...
+func toSha1(str string) string {
+	sha := sha1.New()
+	sha.Write([]byte(str))
+	return hex.EncodeToString(sha.Sum(nil))
+}
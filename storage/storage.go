@@ -0,0 +1,93 @@
+// Package storage defines the Storage contract shared by every backend
+// (redis, memory, postgres) and the primitives they all build on.
+package storage
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Storage is the interface every backend must satisfy to back the
+// shortlink service. Every method takes a context so a cancelled or
+// timed-out HTTP request aborts the backend call it's waiting on.
+// NextID lets each backend reserve ids the way that fits it best: a
+// Redis INCR, a Postgres sequence, or an atomic counter in memory.
+type Storage interface {
+	NextID(ctx context.Context) (int64, error)
+	Shorten(ctx context.Context, url string, exp int64) (string, error)
+	ShortenCustom(ctx context.Context, url string, exp int64, alias string) (string, error)
+	ShortenSigned(ctx context.Context, url string, exp int64) (string, error)
+	ShortlinkInfo(ctx context.Context, eid string) (interface{}, error)
+	Unshorten(ctx context.Context, eid string) (string, error)
+}
+
+// StatusError carries an HTTP status code alongside the underlying error so
+// handlers can respond with the right code without re-inspecting the error.
+type StatusError struct {
+	Code int
+	Err  error
+}
+
+func (se StatusError) Error() string {
+	return se.Err.Error()
+}
+
+// URLDetail is the JSON payload stored alongside each shortlink.
+type URLDetail struct {
+	URL                 string        `json:"url"`
+	CreatedAt           string        `json:"created_at"`
+	ExpirationInMinutes time.Duration `json:"expiration_in_minutes"`
+}
+
+// macSize is how many bytes of the HMAC-SHA256 digest we keep: enough to
+// make forging a code impractical while keeping the signed code short.
+const macSize = 8
+
+// SignCode signs id (and, when exp is non-zero, exp as an optional
+// TTL-in-URL) and returns "<id>.<sig>" or "<id>.<exp>.<sig>".
+func SignCode(key []byte, id string, exp int64) string {
+	if exp == 0 {
+		return id + "." + mac(key, id+"|0")
+	}
+	expStr := strconv.FormatInt(exp, 10)
+	return id + "." + expStr + "." + mac(key, id+"|"+expStr)
+}
+
+// VerifyCode splits a signed code produced by SignCode, recomputes its
+// HMAC and reports whether the signature matches. ok is false for any
+// malformed or tampered code, in which case id/exp must not be trusted.
+func VerifyCode(key []byte, code string) (id string, exp int64, ok bool) {
+	parts := strings.Split(code, ".")
+
+	switch len(parts) {
+	case 2:
+		id, sig := parts[0], parts[1]
+		if mac(key, id+"|0") != sig {
+			return "", 0, false
+		}
+		return id, 0, true
+	case 3:
+		id, expStr, sig := parts[0], parts[1], parts[2]
+		if mac(key, id+"|"+expStr) != sig {
+			return "", 0, false
+		}
+		exp, err := strconv.ParseInt(expStr, 10, 64)
+		if err != nil {
+			return "", 0, false
+		}
+		return id, exp, true
+	default:
+		return "", 0, false
+	}
+}
+
+func mac(key []byte, msg string) string {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(msg))
+	return base64.RawURLEncoding.EncodeToString(h.Sum(nil)[:macSize])
+}
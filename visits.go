@@ -0,0 +1,44 @@
+package main
+
+import "time"
+
+// visitWorkers bounds how many RecordVisit calls can run concurrently so a
+// burst of redirects can't open unbounded goroutines against Redis.
+const visitWorkers = 8
+
+// visitQueueSize is the number of pending visits we buffer before a
+// redirect just drops the analytics write rather than blocking on it.
+const visitQueueSize = 256
+
+type visit struct {
+	a       Analytics
+	eid     string
+	ts      time.Time
+	referer string
+	ua      string
+	ip      string
+}
+
+var visitQueue = make(chan visit, visitQueueSize)
+
+func init() {
+	for i := 0; i < visitWorkers; i++ {
+		go visitWorker()
+	}
+}
+
+func visitWorker() {
+	for v := range visitQueue {
+		v.a.RecordVisit(v.eid, v.ts, v.referer, v.ua, v.ip)
+	}
+}
+
+// enqueueVisit hands a visit off to the worker pool. It never blocks the
+// caller: if the queue is full the visit is dropped rather than slowing
+// down the redirect.
+func enqueueVisit(v visit) {
+	select {
+	case visitQueue <- v:
+	default:
+	}
+}